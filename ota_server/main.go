@@ -1,28 +1,210 @@
 package main
 
 import (
+	"crypto/ed25519"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
 var (
-	firmwareDir = "./firmware"
-	debugMode   = os.Getenv("DEBUG") == "true"
-	versions    = struct {
+	firmwareDir  = "./firmware"
+	releasesFile = "./releases.json"
+	stateFile    = "./state.json"
+	keysDir      = "./keys"
+	debugMode    = os.Getenv("DEBUG") == "true"
+	legacyMD5    = os.Getenv("LEGACY_MD5") == "true"
+	promoteAfter = parseDurationEnv("PROMOTE_AFTER", 10*time.Minute)
+	versions     = struct {
 		sync.RWMutex
-		latest string
-	}{latest: "1.1.0"}
+		latest        map[string]string
+		previousGood  map[string]string
+		pendingSince  map[string]time.Time
+		pendingFailed map[string]bool
+		rolledBack    map[string]bool
+	}{
+		latest:        make(map[string]string),
+		previousGood:  make(map[string]string),
+		pendingSince:  make(map[string]time.Time),
+		pendingFailed: make(map[string]bool),
+		rolledBack:    make(map[string]bool),
+	}
+	trustedKeys = map[string]ed25519.PublicKey{}
+)
+
+// versionStateSnapshot is the on-disk shape of state.json: just the two
+// pointers operators care about recovering across restarts.
+type versionStateSnapshot struct {
+	Latest       map[string]string `json:"latest"`
+	PreviousGood map[string]string `json:"previous_good"`
+}
+
+func parseDurationEnv(key string, fallback time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		logError("Invalid duration env var, using default", err, "env", key, "value", val)
+		return fallback
+	}
+	return d
+}
+
+// Device records an OTA client the server has seen, either through a
+// firmware download, a check-in, or an update result report.
+type Device struct {
+	ID                string    `json:"id"`
+	Board             string    `json:"board"`
+	LastSeen          time.Time `json:"last_seen"`
+	CurrentVersion    string    `json:"current_version"`
+	LastUpdateAttempt time.Time `json:"last_update_attempt,omitempty"`
+	LastUpdateResult  string    `json:"last_update_result,omitempty"`
+	IP                string    `json:"ip"`
+	RSSI              int       `json:"rssi,omitempty"`
+	FreeHeap          int       `json:"free_heap,omitempty"`
+}
+
+var devicesFile = "./devices.json"
+
+var deviceState = struct {
+	sync.RWMutex
+	items map[string]*Device
+}{items: make(map[string]*Device)}
+
+// FirmwareManifest is the signed sidecar persisted alongside each firmware
+// binary so ESP-side OTA code can verify an image before flashing it.
+type FirmwareManifest struct {
+	SHA256              string    `json:"sha256"`
+	Size                int64     `json:"size"`
+	Signature           string    `json:"signature"`
+	SignerKeyID         string    `json:"signer_key_id"`
+	UploadedAt          time.Time `json:"uploaded_at"`
+	Board               string    `json:"board"`
+	MinSupportedVersion string    `json:"min_supported_version,omitempty"`
+}
+
+// Release assigns an already-uploaded firmware build to a rollout channel at
+// a target cohort percentage, decoupling "uploaded" from "deployed".
+type Release struct {
+	Board     string    `json:"board"`
+	Version   string    `json:"version"`
+	Channel   string    `json:"channel"` // dev|beta|stable
+	Cohort    int       `json:"cohort"`  // 0..100, percentage of devices eligible
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var releaseState = struct {
+	sync.RWMutex
+	items []Release
+}{}
+
+var validChannels = map[string]bool{"dev": true, "beta": true, "stable": true}
+
+// Event is a single occurrence broadcast over the /events SSE stream.
+type Event struct {
+	Seq  uint64          `json:"seq"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+const (
+	eventBacklogLimit  = 1000
+	eventSubscriberBuf = 256 // per-subscriber ring buffer size; drop-oldest when full
 )
 
+// eventBus is a small in-process pub/sub: each subscriber gets its own
+// buffered channel so a slow reader can't block Emit for everyone else.
+type eventBus struct {
+	sync.Mutex
+	subscribers map[chan Event]struct{}
+	backlog     []Event
+	nextSeq     uint64
+}
+
+var events = &eventBus{subscribers: make(map[chan Event]struct{})}
+
+// Emit broadcasts a typed event to every subscriber and appends it to the
+// bounded backlog so reconnecting clients can resume with ?since=<seq>.
+func (b *eventBus) Emit(eventType string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logError("Failed to marshal event data", err, "type", eventType)
+		raw = nil
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.nextSeq++
+	evt := Event{Seq: b.nextSeq, Type: eventType, Time: time.Now(), Data: raw}
+
+	b.backlog = append(b.backlog, evt)
+	if len(b.backlog) > eventBacklogLimit {
+		b.backlog = b.backlog[len(b.backlog)-eventBacklogLimit:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			// Subscriber's buffer is full - drop its oldest event and retry
+			// so a slow dashboard can't stall emission for anyone else.
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- evt:
+			default:
+			}
+		}
+	}
+}
+
+// Subscribe registers a new subscriber channel and returns any backlog
+// entries newer than since (0 means "from the beginning of the backlog").
+func (b *eventBus) Subscribe(since uint64) (chan Event, []Event) {
+	b.Lock()
+	defer b.Unlock()
+
+	ch := make(chan Event, eventSubscriberBuf)
+	b.subscribers[ch] = struct{}{}
+
+	var backfill []Event
+	for _, evt := range b.backlog {
+		if evt.Seq > since {
+			backfill = append(backfill, evt)
+		}
+	}
+	return ch, backfill
+}
+
+// Unsubscribe removes a subscriber channel registered by Subscribe.
+func (b *eventBus) Unsubscribe(ch chan Event) {
+	b.Lock()
+	delete(b.subscribers, ch)
+	b.Unlock()
+}
+
 // Structured logging helper
 func logInfo(msg string, fields ...string) {
 	timestamp := time.Now().Format("2006-01-02T15:04:05.000Z")
@@ -77,12 +259,13 @@ func logRequest(next http.HandlerFunc) http.HandlerFunc {
 
 		// Log all requests in debug mode, only non-health-checks in normal mode
 		if debugMode || !isHealthCheck {
-			clientVersion := getClientVersion(r)
+			clientBoard, clientVersion := getClientTarget(r)
 			logInfo("HTTP request started",
 				"method", r.Method,
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
 				"user_agent", r.UserAgent(),
+				"client_board", clientBoard,
 				"client_version", clientVersion,
 			)
 		}
@@ -116,23 +299,49 @@ func (rw *responseWrapper) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush lets responseWrapper satisfy http.Flusher when the wrapped
+// ResponseWriter does, so handlers like streamEvents can still flush
+// through logRequest.
+func (rw *responseWrapper) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func main() {
 	if err := os.MkdirAll(firmwareDir, 0755); err != nil {
 		logError("Failed to create firmware directory", err, "path", firmwareDir)
 		os.Exit(1)
 	}
 
+	loadReleases()
+	loadDevices()
+	loadVersionState()
+	go runPromotionSoak()
+
+	if err := loadTrustedKeys(); err != nil {
+		logError("Failed to load trusted signing keys - uploads will be rejected until keys are provisioned", err, "keys_dir", keysDir)
+	}
+
 	http.HandleFunc("/upload", logRequest(uploadFirmware))
 	http.HandleFunc("/firmware", logRequest(getFirmware))
 	http.HandleFunc("/version", logRequest(getVersion))
 	http.HandleFunc("/list", logRequest(listFirmware))
 	http.HandleFunc("/delete", logRequest(deleteFirmware))
+	http.HandleFunc("/releases", logRequest(handleReleases))
+	http.HandleFunc("/releases/promote", logRequest(promoteRelease))
+	http.HandleFunc("/manifest", logRequest(getManifest))
+	http.HandleFunc("/checkin", logRequest(checkinDevice))
+	http.HandleFunc("/update-result", logRequest(reportUpdateResult))
+	http.HandleFunc("/devices", logRequest(listDevices))
+	http.HandleFunc("/devices/", logRequest(getDevice))
+	http.HandleFunc("/rollback", logRequest(rollbackFirmware))
+	http.HandleFunc("/events", logRequest(streamEvents))
 
 	logInfo("OTA Server starting",
 		"port", "8080",
 		"debug_mode", fmt.Sprintf("%v", debugMode),
 		"firmware_dir", firmwareDir,
-		"initial_version", versions.latest,
 	)
 
 	if err := http.ListenAndServe(":8080", nil); err != nil {
@@ -141,7 +350,322 @@ func main() {
 	}
 }
 
-// Upload new firmware version
+// streamEvents upgrades the connection to text/event-stream and relays
+// firmware/device/rollout events as they're emitted. Clients can pass
+// ?since=<seq> to resume from the bounded backlog after a reconnect.
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logError("Event stream rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	var since uint64
+	if s := r.URL.Query().Get("since"); s != "" {
+		if parsed, err := strconv.ParseUint(s, 10, 64); err == nil {
+			since = parsed
+		}
+	}
+
+	ch, backfill := events.Subscribe(since)
+	defer events.Unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, evt := range backfill {
+		writeSSEEvent(w, evt)
+	}
+	flusher.Flush()
+
+	logInfo("Event stream subscriber connected",
+		"remote_addr", r.RemoteAddr,
+		"since", fmt.Sprintf("%d", since),
+		"backfill", fmt.Sprintf("%d", len(backfill)),
+	)
+
+	for {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeSSEEvent(w, evt)
+			flusher.Flush()
+		case <-r.Context().Done():
+			logInfo("Event stream subscriber disconnected", "remote_addr", r.RemoteAddr)
+			return
+		}
+	}
+}
+
+// writeSSEEvent writes a single event in text/event-stream wire format.
+func writeSSEEvent(w http.ResponseWriter, evt Event) {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		logError("Failed to marshal SSE event", err, "type", evt.Type)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", evt.Seq, evt.Type, payload)
+}
+
+// loadVersionState restores the latest/previous-good pointers from stateFile at startup.
+func loadVersionState() {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logError("Failed to read state file", err, "path", stateFile)
+		}
+		return
+	}
+
+	var snapshot versionStateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		logError("Failed to parse state file", err, "path", stateFile)
+		return
+	}
+
+	versions.Lock()
+	if snapshot.Latest != nil {
+		versions.latest = snapshot.Latest
+	}
+	if snapshot.PreviousGood != nil {
+		versions.previousGood = snapshot.PreviousGood
+	}
+	versions.Unlock()
+
+	logInfo("Version state loaded", "path", stateFile, "board_count", fmt.Sprintf("%d", len(snapshot.Latest)))
+}
+
+// persistVersionState flushes the latest/previous-good pointers to stateFile.
+func persistVersionState() error {
+	versions.RLock()
+	snapshot := versionStateSnapshot{
+		Latest:       versions.latest,
+		PreviousGood: versions.previousGood,
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	versions.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stateFile, data, 0644)
+}
+
+// promoteToGood marks a board's currently-pending version as the trusted
+// previous-good baseline, provided it's still the version in latest.
+func promoteToGood(board, version string) {
+	if version == "" {
+		return
+	}
+
+	versions.Lock()
+	promoted := false
+	if versions.latest[board] == version {
+		versions.previousGood[board] = version
+		delete(versions.pendingSince, board)
+		promoted = true
+	}
+	versions.Unlock()
+
+	if !promoted {
+		return
+	}
+	if err := persistVersionState(); err != nil {
+		logError("Failed to persist version state", err, "board", board, "version", version)
+	}
+	logInfo("Firmware promoted to previous-good", "board", board, "version", version)
+}
+
+// markPendingFailed records that a device reported a failed update for a
+// board's pending version, blocking soak-based auto-promotion.
+func markPendingFailed(board string) {
+	versions.Lock()
+	versions.pendingFailed[board] = true
+	versions.Unlock()
+}
+
+// runPromotionSoak periodically promotes any board's pending version to
+// previous-good once PROMOTE_AFTER has elapsed with no reported failures.
+func runPromotionSoak() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		checkSoakPromotions()
+	}
+}
+
+func checkSoakPromotions() {
+	now := time.Now()
+
+	versions.Lock()
+	var toPromote []string
+	for board, since := range versions.pendingSince {
+		if versions.pendingFailed[board] {
+			continue
+		}
+		if now.Sub(since) >= promoteAfter {
+			toPromote = append(toPromote, board)
+		}
+	}
+	for _, board := range toPromote {
+		versions.previousGood[board] = versions.latest[board]
+		delete(versions.pendingSince, board)
+	}
+	versions.Unlock()
+
+	if len(toPromote) == 0 {
+		return
+	}
+	if err := persistVersionState(); err != nil {
+		logError("Failed to persist version state", err)
+	}
+	for _, board := range toPromote {
+		logInfo("Firmware auto-promoted to previous-good after soak period", "board", board, "soak", promoteAfter.String())
+	}
+}
+
+// rollbackFirmware atomically swaps a board's latest pointer back to its
+// previous-good version so devices receive the rollback image on their next poll.
+func rollbackFirmware(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logError("Rollback rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	board := requestedBoard(r)
+	if board == "" {
+		logError("Rollback rejected - board not specified", nil, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Board not specified", http.StatusBadRequest)
+		return
+	}
+
+	versions.Lock()
+	previousGood, ok := versions.previousGood[board]
+	if !ok || previousGood == "" {
+		versions.Unlock()
+		logError("Rollback failed - no previous-good version recorded", nil, "board", board, "remote_addr", r.RemoteAddr)
+		http.Error(w, "No previous-good version recorded for board", http.StatusNotFound)
+		return
+	}
+
+	oldLatest := versions.latest[board]
+	versions.latest[board] = previousGood
+	versions.rolledBack[board] = true
+	delete(versions.pendingSince, board)
+	delete(versions.pendingFailed, board)
+	versions.Unlock()
+
+	if err := persistVersionState(); err != nil {
+		logError("Rollback failed - persist error", err, "board", board, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unable to persist version state", http.StatusInternalServerError)
+		return
+	}
+
+	logInfo("Firmware rolled back",
+		"board", board,
+		"from_version", oldLatest,
+		"to_version", previousGood,
+		"remote_addr", r.RemoteAddr,
+	)
+
+	events.Emit("rollback_triggered", map[string]interface{}{
+		"board":        board,
+		"from_version": oldLatest,
+		"to_version":   previousGood,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"board":           board,
+		"previous_latest": oldLatest,
+		"rolled_back_to":  previousGood,
+	})
+}
+
+// boardDir returns the on-disk directory path for a given board target. It
+// does not create the directory; uploadFirmware does that before writing.
+func boardDir(board string) string {
+	return filepath.Join(firmwareDir, board)
+}
+
+// highestMatchingVersion returns the highest uploaded version for board that
+// satisfies a relaxed-semver constraint (e.g. "^1.2"), or "" if none match.
+func highestMatchingVersion(board, constraint string) string {
+	files, err := os.ReadDir(boardDir(board))
+	if err != nil {
+		return ""
+	}
+
+	best := ""
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".bin") {
+			continue
+		}
+		version := strings.TrimPrefix(strings.TrimSuffix(file.Name(), ".bin"), "firmware_")
+		if !semverSatisfies(version, constraint) {
+			continue
+		}
+		if best == "" || semverCompare(version, best) > 0 {
+			best = version
+		}
+	}
+	return best
+}
+
+// validBoardPattern restricts board targets to safe identifier characters so
+// a board value can never escape firmwareDir when joined into a path.
+var validBoardPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// isValidBoard rejects anything but a plain identifier - in particular "." and
+// ".." segments and path separators, which would otherwise let a board value
+// passed into boardDir()/filepath.Join escape firmwareDir.
+func isValidBoard(board string) bool {
+	if board == "" || board == "." || board == ".." || strings.Contains(board, "..") {
+		return false
+	}
+	return validBoardPattern.MatchString(board)
+}
+
+// requestedBoard resolves the mandatory board target from the query string,
+// falling back to the x-esp-board header set by provisioned clients. Returns
+// "" if no value is present or the value fails board validation.
+func requestedBoard(r *http.Request) string {
+	board := r.URL.Query().Get("board")
+	if board == "" {
+		board = r.Header.Get("x-esp-board")
+	}
+	if !isValidBoard(board) {
+		return ""
+	}
+	return board
+}
+
+// validVersionPattern restricts version strings to safe semver-like
+// identifiers so a version value can never escape firmwareDir when joined
+// into a "firmware_<version>.bin"/"firmware_<version>.json" path.
+var validVersionPattern = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+
+// isValidVersion rejects anything but a plain version identifier - in
+// particular ".." segments and path separators, which would otherwise let a
+// version value escape boardDir() when joined into a firmware file path.
+func isValidVersion(version string) bool {
+	if version == "" || version == "." || version == ".." || strings.Contains(version, "..") {
+		return false
+	}
+	return validVersionPattern.MatchString(version)
+}
+
+// Upload new firmware version for a specific board target
 func uploadFirmware(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		logError("Upload rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
@@ -169,24 +693,75 @@ func uploadFirmware(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Version not specified", http.StatusBadRequest)
 		return
 	}
+	if !isValidVersion(version) {
+		logError("Upload failed - invalid version", nil, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+
+	board := r.FormValue("board")
+	if board == "" {
+		board = r.Header.Get("x-esp-board")
+	}
+	if board == "" {
+		logError("Upload failed - board not specified", nil, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Board not specified", http.StatusBadRequest)
+		return
+	}
+	if !isValidBoard(board) {
+		logError("Upload failed - invalid board", nil, "board", board, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid board", http.StatusBadRequest)
+		return
+	}
+
+	signatureB64 := r.FormValue("signature")
+	signerKeyID := r.FormValue("signer_key_id")
+	if signatureB64 == "" || signerKeyID == "" {
+		logError("Upload failed - missing signature", nil, "board", board, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "signature and signer_key_id are required", http.StatusBadRequest)
+		return
+	}
+
+	signerKey, ok := trustedKeys[signerKeyID]
+	if !ok {
+		logError("Upload failed - unknown signer key", nil,
+			"board", board, "version", version, "signer_key_id", signerKeyID, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unknown signer key", http.StatusUnauthorized)
+		return
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		logError("Upload failed - malformed signature encoding", err,
+			"board", board, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Malformed signature encoding", http.StatusBadRequest)
+		return
+	}
 
 	logInfo("Firmware upload started",
+		"board", board,
 		"version", version,
 		"filename", header.Filename,
 		"size_bytes", fmt.Sprintf("%d", header.Size),
+		"signer_key_id", signerKeyID,
 		"remote_addr", r.RemoteAddr,
 	)
 
-	// Update latest version and save file
-	versions.Lock()
-	oldVersion := versions.latest
-	versions.latest = version
-	versions.Unlock()
+	if err := os.MkdirAll(boardDir(board), 0755); err != nil {
+		logError("Upload failed - board directory creation error", err,
+			"board", board,
+			"version", version,
+			"remote_addr", r.RemoteAddr,
+		)
+		http.Error(w, "Unable to create board directory", http.StatusInternalServerError)
+		return
+	}
 
-	firmwarePath := filepath.Join(firmwareDir, "firmware_"+version+".bin")
+	firmwarePath := filepath.Join(boardDir(board), "firmware_"+version+".bin")
 	dst, err := os.Create(firmwarePath)
 	if err != nil {
 		logError("Upload failed - file creation error", err,
+			"board", board,
 			"version", version,
 			"path", firmwarePath,
 			"remote_addr", r.RemoteAddr,
@@ -196,9 +771,11 @@ func uploadFirmware(w http.ResponseWriter, r *http.Request) {
 	}
 	defer dst.Close()
 
-	bytesWritten, err := io.Copy(dst, file)
+	hasher := sha256.New()
+	bytesWritten, err := io.Copy(io.MultiWriter(dst, hasher), file)
 	if err != nil {
 		logError("Upload failed - file write error", err,
+			"board", board,
 			"version", version,
 			"path", firmwarePath,
 			"remote_addr", r.RemoteAddr,
@@ -207,21 +784,85 @@ func uploadFirmware(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	md5Hash := getMD5Hash(firmwarePath)
+	sha256Hash := hex.EncodeToString(hasher.Sum(nil))
+	if !ed25519.Verify(signerKey, hasher.Sum(nil), signature) {
+		os.Remove(firmwarePath)
+		logError("Upload rejected - invalid firmware signature", nil,
+			"board", board, "version", version, "signer_key_id", signerKeyID, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid firmware signature", http.StatusUnauthorized)
+		return
+	}
+
+	manifest := FirmwareManifest{
+		SHA256:              sha256Hash,
+		Size:                bytesWritten,
+		Signature:           signatureB64,
+		SignerKeyID:         signerKeyID,
+		UploadedAt:          time.Now(),
+		Board:               board,
+		MinSupportedVersion: r.FormValue("min_supported_version"),
+	}
+	if err := writeManifest(board, version, manifest); err != nil {
+		os.Remove(firmwarePath)
+		logError("Upload failed - manifest write error", err,
+			"board", board, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unable to persist firmware manifest", http.StatusInternalServerError)
+		return
+	}
+
+	// Advance latest only if this upload is newer, so re-uploading an older
+	// hotfix after a newer release can't accidentally trigger a downgrade.
+	versions.Lock()
+	oldVersion := versions.latest[board]
+	advanced := oldVersion == "" || semverCompare(version, oldVersion) > 0
+	if advanced {
+		versions.latest[board] = version
+		versions.pendingSince[board] = time.Now()
+		versions.pendingFailed[board] = false
+		versions.rolledBack[board] = false
+	}
+	versions.Unlock()
+
+	if advanced {
+		if err := persistVersionState(); err != nil {
+			logError("Failed to persist version state", err, "board", board, "version", version, "remote_addr", r.RemoteAddr)
+		}
+	} else {
+		logInfo("Firmware upload stored without advancing latest - not newer than current",
+			"board", board, "version", version, "current_latest", oldVersion, "remote_addr", r.RemoteAddr)
+	}
+
+	var md5Hash string
+	if legacyMD5 {
+		md5Hash = getMD5Hash(firmwarePath)
+	}
+
 	logInfo("Firmware upload completed successfully",
+		"board", board,
 		"version", version,
 		"old_version", oldVersion,
 		"filename", header.Filename,
 		"size_bytes", fmt.Sprintf("%d", bytesWritten),
 		"path", firmwarePath,
+		"sha256", sha256Hash,
+		"signer_key_id", signerKeyID,
 		"md5", md5Hash,
 		"remote_addr", r.RemoteAddr,
 	)
 
-	fmt.Fprintf(w, "Firmware version %s uploaded successfully", version)
+	events.Emit("firmware_uploaded", map[string]interface{}{
+		"board":         board,
+		"version":       version,
+		"old_version":   oldVersion,
+		"sha256":        sha256Hash,
+		"signer_key_id": signerKeyID,
+		"size":          bytesWritten,
+	})
+
+	fmt.Fprintf(w, "Firmware version %s for board %s uploaded successfully", version, board)
 }
 
-// Download firmware (latest or specific version)
+// Download firmware (latest or specific version) for a specific board target
 func getFirmware(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		logError("Firmware download rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
@@ -229,29 +870,89 @@ func getFirmware(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	board := requestedBoard(r)
+	if board == "" {
+		logError("Firmware download rejected - board not specified", nil, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Board not specified", http.StatusBadRequest)
+		return
+	}
+
 	requestedVersion := r.URL.Query().Get("version")
-	clientVersion := getClientVersion(r)
+	if requestedVersion != "" && !isValidVersion(requestedVersion) {
+		logError("Firmware download rejected - invalid version", nil, "board", board, "version", requestedVersion, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	_, clientVersion := getClientTarget(r)
+
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		channel = r.Header.Get("x-esp-channel")
+	}
+	if channel == "" {
+		channel = "stable"
+	}
+
+	deviceID := deviceIdentifier(r)
+	bucket := deviceBucket(deviceID)
 
 	versions.RLock()
-	targetVersion := versions.latest
+	forcedRollback := versions.rolledBack[board]
 	versions.RUnlock()
 
+	var targetVersion string
+	if forcedRollback {
+		// An operator-triggered rollback overrides staged rollout channels
+		// until the next upload supersedes it.
+		versions.RLock()
+		targetVersion = versions.latest[board]
+		versions.RUnlock()
+	} else {
+		targetVersion = resolveReleaseVersion(board, channel, bucket)
+		if targetVersion == "" {
+			versions.RLock()
+			targetVersion = versions.latest[board]
+			versions.RUnlock()
+		}
+	}
+
+	constraint := r.URL.Query().Get("constraint")
+
 	if requestedVersion != "" {
 		targetVersion = requestedVersion
+	} else if constraint != "" {
+		targetVersion = highestMatchingVersion(board, constraint)
 	}
 
 	logDebug("Firmware download request",
+		"board", board,
+		"channel", channel,
+		"device_id", deviceID,
+		"cohort_bucket", fmt.Sprintf("%d", bucket),
 		"client_version", clientVersion,
 		"requested_version", requestedVersion,
+		"constraint", constraint,
 		"target_version", targetVersion,
 		"user_agent", r.UserAgent(),
 		"remote_addr", r.RemoteAddr,
 	)
 
-	firmwarePath := filepath.Join(firmwareDir, "firmware_"+targetVersion+".bin")
+	upsertDevice(deviceID, board, clientVersion, remoteIP(r), targetVersion != "" && targetVersion != clientVersion)
+
+	if targetVersion == "" {
+		logError("Firmware download failed - no firmware available for board", nil,
+			"board", board,
+			"remote_addr", r.RemoteAddr,
+		)
+		http.Error(w, "Firmware not found", http.StatusNotFound)
+		return
+	}
+
+	firmwarePath := filepath.Join(boardDir(board), "firmware_"+targetVersion+".bin")
 	fileInfo, err := os.Stat(firmwarePath)
 	if os.IsNotExist(err) {
 		logError("Firmware download failed - file not found", nil,
+			"board", board,
 			"target_version", targetVersion,
 			"path", firmwarePath,
 			"remote_addr", r.RemoteAddr,
@@ -263,6 +964,7 @@ func getFirmware(w http.ResponseWriter, r *http.Request) {
 	// Check if client already has latest version (ESP8266 OTA optimization)
 	if requestedVersion == "" && clientVersion == targetVersion {
 		logInfo("Firmware download - no update needed",
+			"board", board,
 			"client_version", clientVersion,
 			"latest_version", targetVersion,
 			"remote_addr", r.RemoteAddr,
@@ -272,23 +974,45 @@ func getFirmware(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set firmware download headers
-	md5Hash := getMD5Hash(firmwarePath)
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Disposition", "attachment; filename=firmware.bin")
-	w.Header().Set("x-MD5", md5Hash)
+
+	var sha256Hash string
+	if manifest, err := loadManifest(board, targetVersion); err == nil {
+		sha256Hash = manifest.SHA256
+		w.Header().Set("x-Firmware-SHA256", manifest.SHA256)
+		w.Header().Set("x-Firmware-Signature", manifest.Signature)
+		w.Header().Set("x-Firmware-Signer", manifest.SignerKeyID)
+	} else {
+		logDebug("Firmware download - no manifest found", "board", board, "version", targetVersion)
+	}
+
+	var md5Hash string
+	if legacyMD5 {
+		md5Hash = getMD5Hash(firmwarePath)
+		w.Header().Set("x-MD5", md5Hash)
+	}
 
 	logInfo("Firmware download started",
+		"board", board,
 		"client_version", clientVersion,
 		"target_version", targetVersion,
 		"file_size", fmt.Sprintf("%d", fileInfo.Size()),
+		"sha256", sha256Hash,
 		"md5", md5Hash,
 		"remote_addr", r.RemoteAddr,
 	)
 
+	events.Emit("firmware_downloaded", map[string]interface{}{
+		"board":     board,
+		"version":   targetVersion,
+		"device_id": deviceID,
+	})
+
 	http.ServeFile(w, r, firmwarePath)
 }
 
-// Get current firmware version (for health checks and ESP8266)
+// Get current firmware version for a specific board (for health checks and ESP8266)
 func getVersion(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		logError("Version request rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
@@ -296,17 +1020,24 @@ func getVersion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	versions.RLock()
-	current := versions.latest
+	board := requestedBoard(r)
+	if board == "" {
+		logError("Version request rejected - board not specified", nil, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Board not specified", http.StatusBadRequest)
+		return
+	}
+
+	versions.RLock()
+	current := versions.latest[board]
 	versions.RUnlock()
 
-	logDebug("Version request", "current_version", current, "remote_addr", r.RemoteAddr)
+	logDebug("Version request", "board", board, "current_version", current, "remote_addr", r.RemoteAddr)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"version": current})
+	json.NewEncoder(w).Encode(map[string]string{"board": board, "version": current})
 }
 
-// List all available firmware versions with metadata
+// List all available firmware versions with metadata, grouped by board target
 func listFirmware(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		logError("List request rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
@@ -314,23 +1045,13 @@ func listFirmware(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	files, err := os.ReadDir(firmwareDir)
+	boardEntries, err := os.ReadDir(firmwareDir)
 	if err != nil {
 		logError("List failed - directory read error", err, "firmware_dir", firmwareDir, "remote_addr", r.RemoteAddr)
 		http.Error(w, "Unable to read firmware directory", http.StatusInternalServerError)
 		return
 	}
 
-	versions.RLock()
-	currentLatest := versions.latest
-	versions.RUnlock()
-
-	logInfo("Firmware list request",
-		"current_version", currentLatest,
-		"total_files", fmt.Sprintf("%d", len(files)),
-		"remote_addr", r.RemoteAddr,
-	)
-
 	type FirmwareInfo struct {
 		Version     string `json:"version"`
 		Filename    string `json:"filename"`
@@ -341,49 +1062,74 @@ func listFirmware(w http.ResponseWriter, r *http.Request) {
 		DownloadURL string `json:"download_url"`
 	}
 
-	var firmwareList []FirmwareInfo
+	firmwareByBoard := make(map[string][]FirmwareInfo)
 	var totalSize int64
+	var totalFiles int
 
-	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".bin") {
+	for _, boardEntry := range boardEntries {
+		if !boardEntry.IsDir() {
 			continue
 		}
+		board := boardEntry.Name()
 
-		filePath := filepath.Join(firmwareDir, file.Name())
-		fileInfo, _ := file.Info()
+		files, err := os.ReadDir(filepath.Join(firmwareDir, board))
+		if err != nil {
+			logError("List failed - board directory read error", err, "board", board, "remote_addr", r.RemoteAddr)
+			continue
+		}
 
-		// Extract version from filename: firmware_X.X.X.bin
-		version := strings.TrimPrefix(strings.TrimSuffix(file.Name(), ".bin"), "firmware_")
-		totalSize += fileInfo.Size()
+		var boardList []FirmwareInfo
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".bin") {
+				continue
+			}
+
+			filePath := filepath.Join(firmwareDir, board, file.Name())
+			fileInfo, _ := file.Info()
+
+			// Extract version from filename: firmware_X.X.X.bin
+			version := strings.TrimPrefix(strings.TrimSuffix(file.Name(), ".bin"), "firmware_")
+			totalSize += fileInfo.Size()
+			totalFiles++
+
+			boardList = append(boardList, FirmwareInfo{
+				Version:     version,
+				Filename:    file.Name(),
+				Size:        fileInfo.Size(),
+				Modified:    fileInfo.ModTime().Format("2006-01-02 15:04:05"),
+				MD5:         getMD5Hash(filePath),
+				DownloadURL: "/firmware?board=" + board + "&version=" + version,
+			})
+		}
 
-		firmwareList = append(firmwareList, FirmwareInfo{
-			Version:     version,
-			Filename:    file.Name(),
-			Size:        fileInfo.Size(),
-			Modified:    fileInfo.ModTime().Format("2006-01-02 15:04:05"),
-			MD5:         getMD5Hash(filePath),
-			IsLatest:    version == currentLatest,
-			DownloadURL: "/firmware?version=" + version,
-		})
+		if boardList != nil {
+			sort.Slice(boardList, func(i, j int) bool {
+				return semverCompare(boardList[i].Version, boardList[j].Version) > 0
+			})
+			for i := range boardList {
+				boardList[i].IsLatest = i == 0
+			}
+			firmwareByBoard[board] = boardList
+		}
 	}
 
 	logInfo("Firmware list prepared",
-		"firmware_count", fmt.Sprintf("%d", len(firmwareList)),
+		"board_count", fmt.Sprintf("%d", len(firmwareByBoard)),
+		"total_files", fmt.Sprintf("%d", totalFiles),
 		"total_size_bytes", fmt.Sprintf("%d", totalSize),
 		"remote_addr", r.RemoteAddr,
 	)
 
 	response := map[string]interface{}{
-		"current_version": currentLatest,
-		"firmware_count":  len(firmwareList),
-		"firmware_list":   firmwareList,
+		"board_count": len(firmwareByBoard),
+		"firmware":    firmwareByBoard,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// Delete specific firmware version
+// Delete specific firmware version for a specific board
 func deleteFirmware(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		logError("Delete request rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
@@ -391,18 +1137,31 @@ func deleteFirmware(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	board := requestedBoard(r)
+	if board == "" {
+		logError("Delete failed - board not specified", nil, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Board not specified", http.StatusBadRequest)
+		return
+	}
+
 	version := r.URL.Query().Get("version")
 	if version == "" {
-		logError("Delete failed - version not specified", nil, "remote_addr", r.RemoteAddr)
+		logError("Delete failed - version not specified", nil, "board", board, "remote_addr", r.RemoteAddr)
 		http.Error(w, "Version not specified", http.StatusBadRequest)
 		return
 	}
+	if !isValidVersion(version) {
+		logError("Delete failed - invalid version", nil, "board", board, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
 
-	firmwarePath := filepath.Join(firmwareDir, "firmware_"+version+".bin")
+	firmwarePath := filepath.Join(boardDir(board), "firmware_"+version+".bin")
 
 	// Check if file exists before deletion
 	if _, err := os.Stat(firmwarePath); os.IsNotExist(err) {
 		logError("Delete failed - firmware not found", nil,
+			"board", board,
 			"version", version,
 			"path", firmwarePath,
 			"remote_addr", r.RemoteAddr,
@@ -413,6 +1172,7 @@ func deleteFirmware(w http.ResponseWriter, r *http.Request) {
 
 	if err := os.Remove(firmwarePath); err != nil {
 		logError("Delete failed - file removal error", err,
+			"board", board,
 			"version", version,
 			"path", firmwarePath,
 			"remote_addr", r.RemoteAddr,
@@ -421,27 +1181,688 @@ func deleteFirmware(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := os.Remove(manifestPath(board, version)); err != nil && !os.IsNotExist(err) {
+		logError("Delete warning - manifest removal error", err,
+			"board", board,
+			"version", version,
+			"remote_addr", r.RemoteAddr,
+		)
+	}
+
 	logInfo("Firmware deleted successfully",
+		"board", board,
 		"version", version,
 		"path", firmwarePath,
 		"remote_addr", r.RemoteAddr,
 	)
 
-	fmt.Fprintf(w, "Firmware version %s deleted successfully", version)
+	events.Emit("firmware_deleted", map[string]interface{}{
+		"board":   board,
+		"version": version,
+	})
+
+	fmt.Fprintf(w, "Firmware version %s for board %s deleted successfully", version, board)
+}
+
+// loadTrustedKeys reads Ed25519 public keys from keysDir, one base64-encoded
+// key per *.pub file, keyed by filename (without extension) as the signer_key_id.
+func loadTrustedKeys() error {
+	entries, err := os.ReadDir(keysDir)
+	if err != nil {
+		return err
+	}
+
+	loaded := map[string]ed25519.PublicKey{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pub") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(keysDir, entry.Name()))
+		if err != nil {
+			logError("Failed to read signing key", err, "file", entry.Name())
+			continue
+		}
+
+		keyBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(keyBytes) != ed25519.PublicKeySize {
+			logError("Failed to parse signing key - expected base64-encoded Ed25519 public key", err, "file", entry.Name())
+			continue
+		}
+
+		keyID := strings.TrimSuffix(entry.Name(), ".pub")
+		loaded[keyID] = ed25519.PublicKey(keyBytes)
+	}
+
+	trustedKeys = loaded
+	logInfo("Trusted signing keys loaded", "keys_dir", keysDir, "count", fmt.Sprintf("%d", len(trustedKeys)))
+	return nil
+}
+
+// manifestPath returns the sidecar manifest path for a given board+version.
+func manifestPath(board, version string) string {
+	return filepath.Join(boardDir(board), "firmware_"+version+".json")
+}
+
+// writeManifest persists the signed firmware manifest next to its binary.
+func writeManifest(board, version string, manifest FirmwareManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(board, version), data, 0644)
+}
+
+// loadManifest reads the signed firmware manifest for a given board+version.
+func loadManifest(board, version string) (*FirmwareManifest, error) {
+	data, err := os.ReadFile(manifestPath(board, version))
+	if err != nil {
+		return nil, err
+	}
+	var manifest FirmwareManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// getManifest exposes a firmware's signed manifest so ESP-side OTA code can
+// verify sha256/signature before flashing.
+func getManifest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logError("Manifest request rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	board := requestedBoard(r)
+	if board == "" {
+		logError("Manifest request rejected - board not specified", nil, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Board not specified", http.StatusBadRequest)
+		return
+	}
+
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		http.Error(w, "Version not specified", http.StatusBadRequest)
+		return
+	}
+	if !isValidVersion(version) {
+		logError("Manifest request rejected - invalid version", nil, "board", board, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+
+	manifest, err := loadManifest(board, version)
+	if err != nil {
+		logError("Manifest request failed - not found", err, "board", board, "version", version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Manifest not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manifest)
+}
+
+// loadReleases restores rollout state from releasesFile at startup.
+func loadReleases() {
+	data, err := os.ReadFile(releasesFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logError("Failed to read releases file", err, "path", releasesFile)
+		}
+		return
+	}
+
+	var items []Release
+	if err := json.Unmarshal(data, &items); err != nil {
+		logError("Failed to parse releases file", err, "path", releasesFile)
+		return
+	}
+
+	releaseState.Lock()
+	releaseState.items = items
+	releaseState.Unlock()
+
+	logInfo("Releases loaded", "path", releasesFile, "count", fmt.Sprintf("%d", len(items)))
+}
+
+// persistReleases writes the current rollout state to releasesFile.
+func persistReleases() error {
+	releaseState.RLock()
+	data, err := json.MarshalIndent(releaseState.items, "", "  ")
+	releaseState.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(releasesFile, data, 0644)
+}
+
+// deviceBucket hashes a device identifier into a stable 0-99 cohort bucket.
+func deviceBucket(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % 100)
+}
+
+// resolveReleaseVersion returns the highest version released to board+channel
+// whose cohort covers the given bucket, or "" if no release matches.
+func resolveReleaseVersion(board, channel string, bucket int) string {
+	releaseState.RLock()
+	defer releaseState.RUnlock()
+
+	best := ""
+	for _, rel := range releaseState.items {
+		if rel.Board != board || rel.Channel != channel || rel.Cohort <= bucket {
+			continue
+		}
+		if best == "" || semverCompare(rel.Version, best) > 0 {
+			best = rel.Version
+		}
+	}
+	return best
+}
+
+// handleReleases creates a new channel assignment (POST) or lists existing
+// ones, optionally filtered by board/channel (GET).
+func handleReleases(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		createRelease(w, r)
+	case http.MethodGet:
+		listReleases(w, r)
+	default:
+		logError("Releases request rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createRelease assigns uploaded firmware to a rollout channel at a target cohort percentage.
+func createRelease(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Board   string `json:"board"`
+		Version string `json:"version"`
+		Channel string `json:"channel"`
+		Cohort  int    `json:"cohort"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logError("Release creation failed - invalid body", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Board == "" || req.Version == "" {
+		http.Error(w, "board and version are required", http.StatusBadRequest)
+		return
+	}
+	if !isValidBoard(req.Board) {
+		http.Error(w, "Invalid board", http.StatusBadRequest)
+		return
+	}
+	if !isValidVersion(req.Version) {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	if !validChannels[req.Channel] {
+		http.Error(w, "channel must be one of dev, beta, stable", http.StatusBadRequest)
+		return
+	}
+	if req.Cohort < 0 || req.Cohort > 100 {
+		http.Error(w, "cohort must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+
+	firmwarePath := filepath.Join(boardDir(req.Board), "firmware_"+req.Version+".bin")
+	if _, err := os.Stat(firmwarePath); os.IsNotExist(err) {
+		logError("Release creation failed - firmware not found", nil,
+			"board", req.Board, "version", req.Version, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Firmware not found", http.StatusNotFound)
+		return
+	}
+
+	release := Release{
+		Board:     req.Board,
+		Version:   req.Version,
+		Channel:   req.Channel,
+		Cohort:    req.Cohort,
+		CreatedAt: time.Now(),
+	}
+
+	releaseState.Lock()
+	replaced := false
+	for i, existing := range releaseState.items {
+		if existing.Board == release.Board && existing.Version == release.Version && existing.Channel == release.Channel {
+			releaseState.items[i] = release
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		releaseState.items = append(releaseState.items, release)
+	}
+	releaseState.Unlock()
+
+	if err := persistReleases(); err != nil {
+		logError("Release creation failed - persist error", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unable to persist release", http.StatusInternalServerError)
+		return
+	}
+
+	logInfo("Release created",
+		"board", release.Board,
+		"version", release.Version,
+		"channel", release.Channel,
+		"cohort", fmt.Sprintf("%d", release.Cohort),
+		"remote_addr", r.RemoteAddr,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(release)
+}
+
+// promoteRelease bumps a release's cohort percentage and/or moves it to a new channel.
+func promoteRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logError("Promote rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Board     string `json:"board"`
+		Version   string `json:"version"`
+		Channel   string `json:"channel"`
+		Cohort    *int   `json:"cohort"`
+		PromoteTo string `json:"promote_to"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logError("Promote failed - invalid body", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Cohort != nil && (*req.Cohort < 0 || *req.Cohort > 100) {
+		http.Error(w, "cohort must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	if req.PromoteTo != "" && !validChannels[req.PromoteTo] {
+		http.Error(w, "promote_to must be one of dev, beta, stable", http.StatusBadRequest)
+		return
+	}
+
+	releaseState.Lock()
+	var updated *Release
+	for i, existing := range releaseState.items {
+		if existing.Board == req.Board && existing.Version == req.Version && existing.Channel == req.Channel {
+			if req.Cohort != nil {
+				releaseState.items[i].Cohort = *req.Cohort
+			}
+			if req.PromoteTo != "" {
+				releaseState.items[i].Channel = req.PromoteTo
+			}
+			updated = &releaseState.items[i]
+			break
+		}
+	}
+	releaseState.Unlock()
+
+	if updated == nil {
+		logError("Promote failed - release not found", nil,
+			"board", req.Board, "version", req.Version, "channel", req.Channel, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Release not found", http.StatusNotFound)
+		return
+	}
+
+	if err := persistReleases(); err != nil {
+		logError("Promote failed - persist error", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Unable to persist release", http.StatusInternalServerError)
+		return
+	}
+
+	logInfo("Release promoted",
+		"board", updated.Board,
+		"version", updated.Version,
+		"channel", updated.Channel,
+		"cohort", fmt.Sprintf("%d", updated.Cohort),
+		"remote_addr", r.RemoteAddr,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// listReleases returns rollout assignments, optionally filtered by board/channel.
+func listReleases(w http.ResponseWriter, r *http.Request) {
+	boardFilter := r.URL.Query().Get("board")
+	channelFilter := r.URL.Query().Get("channel")
+
+	releaseState.RLock()
+	defer releaseState.RUnlock()
+
+	result := make([]Release, 0, len(releaseState.items))
+	for _, rel := range releaseState.items {
+		if boardFilter != "" && rel.Board != boardFilter {
+			continue
+		}
+		if channelFilter != "" && rel.Channel != channelFilter {
+			continue
+		}
+		result = append(result, rel)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"release_count": len(result),
+		"releases":      result,
+	})
+}
+
+// deviceIdentifier resolves the device's stable identity from the
+// x-esp8266-chipid header, falling back to a synthesized MAC-like ID
+// derived from the client's IP for devices that don't send a chip ID.
+func deviceIdentifier(r *http.Request) string {
+	if id := r.Header.Get("x-esp8266-chipid"); id != "" {
+		return id
+	}
+	return synthesizeDeviceID(r)
+}
+
+func synthesizeDeviceID(r *http.Request) string {
+	h := fnv.New64a()
+	h.Write([]byte(remoteIP(r)))
+	sum := h.Sum64()
+	return fmt.Sprintf("synth-%012x", sum&0xFFFFFFFFFFFF)
+}
+
+// remoteIP strips the port from a request's RemoteAddr.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// loadDevices restores the device inventory snapshot from devicesFile at startup.
+func loadDevices() {
+	data, err := os.ReadFile(devicesFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logError("Failed to read devices file", err, "path", devicesFile)
+		}
+		return
+	}
+
+	var items map[string]*Device
+	if err := json.Unmarshal(data, &items); err != nil {
+		logError("Failed to parse devices file", err, "path", devicesFile)
+		return
+	}
+
+	deviceState.Lock()
+	deviceState.items = items
+	deviceState.Unlock()
+
+	logInfo("Device inventory loaded", "path", devicesFile, "count", fmt.Sprintf("%d", len(items)))
+}
+
+// persistDevices flushes a JSON snapshot of the device inventory to devicesFile.
+func persistDevices() error {
+	deviceState.RLock()
+	data, err := json.MarshalIndent(deviceState.items, "", "  ")
+	deviceState.RUnlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(devicesFile, data, 0644)
+}
+
+// upsertDevice records or updates a device row and flushes the inventory snapshot.
+func upsertDevice(id, board, version, ip string, markAttempt bool) *Device {
+	deviceState.Lock()
+	dev, ok := deviceState.items[id]
+	if !ok {
+		dev = &Device{ID: id}
+		deviceState.items[id] = dev
+	}
+	if board != "" {
+		dev.Board = board
+	}
+	if version != "" {
+		dev.CurrentVersion = version
+	}
+	if ip != "" {
+		dev.IP = ip
+	}
+	if markAttempt {
+		dev.LastUpdateAttempt = time.Now()
+	}
+	dev.LastSeen = time.Now()
+	deviceState.Unlock()
+
+	if err := persistDevices(); err != nil {
+		logError("Failed to persist device inventory", err, "device_id", id)
+	}
+	return dev
+}
+
+// checkinDevice records telemetry a device reports on its own schedule.
+func checkinDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logError("Check-in rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		UptimeSeconds int    `json:"uptime_seconds"`
+		FreeHeap      int    `json:"free_heap"`
+		RSSI          int    `json:"rssi"`
+		Version       string `json:"version"`
+		Board         string `json:"board"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logError("Check-in failed - invalid body", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	board := req.Board
+	if board != "" && !isValidBoard(board) {
+		board = ""
+	}
+	if board == "" {
+		board = requestedBoard(r)
+	}
+	id := deviceIdentifier(r)
+
+	deviceState.Lock()
+	dev, ok := deviceState.items[id]
+	if !ok {
+		dev = &Device{ID: id}
+		deviceState.items[id] = dev
+	}
+	if board != "" {
+		dev.Board = board
+	}
+	if req.Version != "" {
+		dev.CurrentVersion = req.Version
+	}
+	dev.RSSI = req.RSSI
+	dev.FreeHeap = req.FreeHeap
+	dev.IP = remoteIP(r)
+	dev.LastSeen = time.Now()
+	deviceState.Unlock()
+
+	if err := persistDevices(); err != nil {
+		logError("Failed to persist device inventory", err, "device_id", id)
+	}
+
+	logInfo("Device checked in",
+		"device_id", id,
+		"board", board,
+		"version", req.Version,
+		"uptime_seconds", fmt.Sprintf("%d", req.UptimeSeconds),
+		"free_heap", fmt.Sprintf("%d", req.FreeHeap),
+		"rssi", fmt.Sprintf("%d", req.RSSI),
+		"remote_addr", r.RemoteAddr,
+	)
+
+	events.Emit("device_checked_in", map[string]interface{}{
+		"device_id": id,
+		"board":     board,
+		"version":   req.Version,
+		"rssi":      req.RSSI,
+		"free_heap": req.FreeHeap,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// reportUpdateResult records whether a device successfully applied a firmware image.
+func reportUpdateResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		logError("Update result rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Result  string `json:"result"`
+		Reason  string `json:"reason"`
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logError("Update result failed - invalid body", err, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Result != "success" && req.Result != "failure" {
+		http.Error(w, "result must be 'success' or 'failure'", http.StatusBadRequest)
+		return
+	}
+
+	id := deviceIdentifier(r)
+	result := req.Result
+	if req.Reason != "" {
+		result += ": " + req.Reason
+	}
+
+	deviceState.Lock()
+	dev, ok := deviceState.items[id]
+	if !ok {
+		dev = &Device{ID: id}
+		deviceState.items[id] = dev
+	}
+	dev.LastUpdateResult = result
+	dev.LastUpdateAttempt = time.Now()
+	if req.Result == "success" && req.Version != "" {
+		dev.CurrentVersion = req.Version
+	}
+	dev.IP = remoteIP(r)
+	dev.LastSeen = time.Now()
+	board := dev.Board
+	deviceState.Unlock()
+
+	if board != "" {
+		if req.Result == "success" {
+			promoteToGood(board, req.Version)
+		} else {
+			markPendingFailed(board)
+		}
+	}
+
+	if err := persistDevices(); err != nil {
+		logError("Failed to persist device inventory", err, "device_id", id)
+	}
+
+	logInfo("Device reported update result",
+		"device_id", id,
+		"result", req.Result,
+		"reason", req.Reason,
+		"version", req.Version,
+		"remote_addr", r.RemoteAddr,
+	)
+
+	eventType := "update_succeeded"
+	if req.Result == "failure" {
+		eventType = "update_failed"
+	}
+	events.Emit(eventType, map[string]interface{}{
+		"device_id": id,
+		"board":     board,
+		"version":   req.Version,
+		"reason":    req.Reason,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
-// Extract client version from ESP8266 request headers
-func getClientVersion(r *http.Request) string {
+// listDevices returns every device the server has seen, for operators.
+func listDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logError("Devices list rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	deviceState.RLock()
+	list := make([]*Device, 0, len(deviceState.items))
+	for _, dev := range deviceState.items {
+		list = append(list, dev)
+	}
+	deviceState.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"device_count": len(list),
+		"devices":      list,
+	})
+}
+
+// getDevice returns a single device's inventory record by ID.
+func getDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		logError("Device lookup rejected - invalid method", nil, "method", r.Method, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/devices/")
+	if id == "" {
+		http.Error(w, "Device ID not specified", http.StatusBadRequest)
+		return
+	}
+
+	deviceState.RLock()
+	dev, ok := deviceState.items[id]
+	deviceState.RUnlock()
+	if !ok {
+		logError("Device lookup failed - not found", nil, "device_id", id, "remote_addr", r.RemoteAddr)
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dev)
+}
+
+// Extract client board and version from ESP8266 request headers
+func getClientTarget(r *http.Request) (board string, version string) {
+	board = r.Header.Get("x-esp-board")
+
 	if headerVersion := r.Header.Get("x-esp8266-version"); headerVersion != "" {
-		return headerVersion
+		return board, headerVersion
 	}
 
 	userAgent := r.UserAgent()
 	if parts := strings.Split(userAgent, "/"); len(parts) > 1 {
-		return parts[1]
+		return board, parts[1]
 	}
 
-	return ""
+	return board, ""
 }
 
 // Calculate MD5 hash for firmware integrity verification
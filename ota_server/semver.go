@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semverVersion is a relaxed parse of a dotted version string: missing
+// components default to 0, and a leading "v" is tolerated.
+type semverVersion struct {
+	major, minor, patch int
+	prerelease          string
+}
+
+// parseSemver accepts forms like "1.2", "1.2.3", "v1.2.3", and
+// "1.2.3-rc1+build4". Build metadata is parsed off but not compared, per semver.
+func parseSemver(v string) semverVersion {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+
+	if idx := strings.Index(v, "+"); idx != -1 {
+		v = v[:idx]
+	}
+
+	prerelease := ""
+	if idx := strings.Index(v, "-"); idx != -1 {
+		prerelease = v[idx+1:]
+		v = v[:idx]
+	}
+
+	parts := strings.Split(v, ".")
+	var sv semverVersion
+	if len(parts) > 0 {
+		sv.major, _ = strconv.Atoi(parts[0])
+	}
+	if len(parts) > 1 {
+		sv.minor, _ = strconv.Atoi(parts[1])
+	}
+	if len(parts) > 2 {
+		sv.patch, _ = strconv.Atoi(parts[2])
+	}
+	sv.prerelease = prerelease
+	return sv
+}
+
+// semverCompare returns <0, 0, >0 as a<b, a==b, a>b under relaxed semver
+// rules. A version without a prerelease outranks the same version with one.
+func semverCompare(a, b string) int {
+	va, vb := parseSemver(a), parseSemver(b)
+
+	if va.major != vb.major {
+		return va.major - vb.major
+	}
+	if va.minor != vb.minor {
+		return va.minor - vb.minor
+	}
+	if va.patch != vb.patch {
+		return va.patch - vb.patch
+	}
+	if va.prerelease == "" && vb.prerelease != "" {
+		return 1
+	}
+	if va.prerelease != "" && vb.prerelease == "" {
+		return -1
+	}
+	return comparePrerelease(va.prerelease, vb.prerelease)
+}
+
+// comparePrerelease does a natural-sort comparison of two prerelease
+// strings: each is split into alternating runs of digits and non-digits, and
+// corresponding numeric runs are compared by value rather than lexically, so
+// "rc10" correctly sorts after "rc9".
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	aParts, bParts := splitNumericRuns(a), splitNumericRuns(b)
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		an, aIsNum := asNumericRun(aParts[i])
+		bn, bIsNum := asNumericRun(bParts[i])
+		if aIsNum && bIsNum {
+			if an != bn {
+				return an - bn
+			}
+			continue
+		}
+		if c := strings.Compare(aParts[i], bParts[i]); c != 0 {
+			return c
+		}
+	}
+	return len(aParts) - len(bParts)
+}
+
+// splitNumericRuns splits s into alternating runs of digits and non-digits,
+// e.g. "rc10" -> ["rc", "10"], so comparePrerelease can compare numeric runs
+// by value instead of character-by-character.
+func splitNumericRuns(s string) []string {
+	var runs []string
+	start := 0
+	for i := 1; i <= len(s); i++ {
+		if i == len(s) || isDigit(s[i]) != isDigit(s[i-1]) {
+			runs = append(runs, s[start:i])
+			start = i
+		}
+	}
+	return runs
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// asNumericRun reports whether a run produced by splitNumericRuns is a
+// digit run, returning its value when it is.
+func asNumericRun(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// semverSatisfies reports whether version meets constraint. A "^X.Y.Z"
+// constraint matches any version with the same major and >= X.Y.Z, the
+// convention used by the ?constraint= query parameter on /firmware.
+func semverSatisfies(version, constraint string) bool {
+	if constraint == "" {
+		return true
+	}
+
+	if strings.HasPrefix(constraint, "^") {
+		base := strings.TrimPrefix(constraint, "^")
+		vb := parseSemver(base)
+		v := parseSemver(version)
+		if v.major != vb.major {
+			return false
+		}
+		return semverCompare(version, base) >= 0
+	}
+
+	return semverCompare(version, constraint) == 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	cases := []struct {
+		in   string
+		want semverVersion
+	}{
+		{"1.2.3", semverVersion{1, 2, 3, ""}},
+		{"1.2", semverVersion{1, 2, 0, ""}},
+		{"v1.2.3", semverVersion{1, 2, 3, ""}},
+		{"1.2.3-rc1", semverVersion{1, 2, 3, "rc1"}},
+		{"1.2.3+build4", semverVersion{1, 2, 3, ""}},
+		{"1.2.3-rc1+build4", semverVersion{1, 2, 3, "rc1"}},
+	}
+	for _, c := range cases {
+		got := parseSemver(c.in)
+		if got != c.want {
+			t.Errorf("parseSemver(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.4", "1.2.3", 1},
+		{"1.2.3", "1.2.4", -1},
+		{"1.3.0", "1.2.9", 1},
+		{"2.0.0", "1.9.9", 1},
+		{"1.2.3", "1.2.3-rc1", 1},
+		{"1.2.3-rc1", "1.2.3", -1},
+		{"1.2.3-rc10", "1.2.3-rc9", 1},
+		{"1.2.3-rc9", "1.2.3-rc10", -1},
+		{"1.2.3-rc9", "1.2.3-rc9", 0},
+	}
+	for _, c := range cases {
+		if got := sign(semverCompare(c.a, c.b)); got != c.want {
+			t.Errorf("semverCompare(%q, %q) = %d, want sign %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func TestSemverSatisfies(t *testing.T) {
+	cases := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"1.2.3", "", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.4", "1.2.3", false},
+		{"1.2.3", "^1.2.0", true},
+		{"1.5.0", "^1.2.0", true},
+		{"2.0.0", "^1.2.0", false},
+		{"1.1.0", "^1.2.0", false},
+	}
+	for _, c := range cases {
+		if got := semverSatisfies(c.version, c.constraint); got != c.want {
+			t.Errorf("semverSatisfies(%q, %q) = %v, want %v", c.version, c.constraint, got, c.want)
+		}
+	}
+}